@@ -0,0 +1,79 @@
+// Package logqlmodel holds the result types shared by LogQL query evaluation
+// and the downstream shard execution plumbing in
+// pkg/querier/queryrange. It intentionally only carries the shape that
+// plumbing depends on; the full wire types (logproto.Stream, the stats
+// package, HTTP response headers, etc.) live alongside the rest of this
+// repo's request/response codecs, outside this checkout.
+package logqlmodel
+
+import (
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// ValueTypeStreams is the parser.ValueType Streams reports itself as, so it
+// can be used as a Result's Data alongside the promql Vector/Matrix types a
+// metric query returns.
+const ValueTypeStreams parser.ValueType = "streams"
+
+// Result is the result of evaluating a single LogQL query, or one shard or
+// tenant of a larger sharded/fanned-out one. Downstream shard results are
+// merged into a single Result before being handed back up to the query
+// engine.
+type Result struct {
+	// Statistics carries the summary counters accumulated while evaluating
+	// this result, merged across every shard or tenant that contributed.
+	Statistics Statistics
+	// Data holds the query's decoded payload: Streams for a log query, or a
+	// promql/parser.Value (Vector/Matrix) for a metric query.
+	Data parser.Value
+	// Headers are the downstream response headers collected across every
+	// shard or tenant that contributed to this result.
+	Headers []string
+	// Warnings collects non-fatal problems encountered producing this
+	// result, such as a shard or tenant that failed while the query was
+	// running in partial-results mode. They're surfaced to the client as
+	// the "warnings" array on the JSON response.
+	Warnings []string
+}
+
+// Statistics is a minimal stand-in for this repo's full
+// pkg/logqlmodel/stats.Result; it only carries enough shape for
+// cross-shard/tenant result merging to fold summary counters together.
+type Statistics struct {
+	TotalBytesProcessed int64
+}
+
+// Merge folds other's counters into s.
+func (s *Statistics) Merge(other Statistics) {
+	s.TotalBytesProcessed += other.TotalBytesProcessed
+}
+
+// Streams is the Data of a Result for a log (non-metric) query. It
+// implements parser.Value so it can be returned alongside the promql
+// Vector/Matrix types a metric query's Data holds.
+type Streams []Stream
+
+// String implements parser.Value.
+func (Streams) String() string { return "" }
+
+// Type implements parser.Value.
+func (Streams) Type() parser.ValueType { return ValueTypeStreams }
+
+// Stream is a minimal stand-in for this repo's full logproto.Stream; it only
+// carries the label set, since that's all cross-tenant result merging
+// inspects.
+type Stream struct {
+	Labels string
+}
+
+// WarningsFromResults collects the Warnings of every result, in order, into
+// a single slice. It's the hook the HTTP response codec (outside this
+// checkout) uses to populate the "warnings" array of the JSON response once
+// a query's per-shard/tenant Results have been merged.
+func WarningsFromResults(results ...Result) []string {
+	var warnings []string
+	for _, r := range results {
+		warnings = append(warnings, r.Warnings...)
+	}
+	return warnings
+}
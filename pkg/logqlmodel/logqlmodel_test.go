@@ -0,0 +1,20 @@
+package logqlmodel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarningsFromResults_CollectsInOrder(t *testing.T) {
+	warnings := WarningsFromResults(
+		Result{Warnings: []string{"tenant a: unavailable"}},
+		Result{},
+		Result{Warnings: []string{"tenant c: timeout"}},
+	)
+	require.Equal(t, []string{"tenant a: unavailable", "tenant c: timeout"}, warnings)
+}
+
+func TestWarningsFromResults_NoWarnings(t *testing.T) {
+	require.Nil(t, WarningsFromResults(Result{}, Result{}))
+}
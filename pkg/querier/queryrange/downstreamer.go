@@ -4,18 +4,23 @@ import (
 	"context"
 	"fmt"
 	reflect "reflect"
+	"sort"
+	"sync"
 
 	"github.com/go-kit/log/level"
 	"github.com/grafana/dskit/tenant"
 	"github.com/opentracing/opentracing-go"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql"
 	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/weaveworks/common/user"
 
 	"github.com/grafana/loki/pkg/loghttp"
 	"github.com/grafana/loki/pkg/logql"
 	"github.com/grafana/loki/pkg/logqlmodel"
 	"github.com/grafana/loki/pkg/querier/queryrange/queryrangebase"
+	"github.com/grafana/loki/pkg/querier/queryrange/scheduler"
 	"github.com/grafana/loki/pkg/util/spanlogger"
 )
 
@@ -26,6 +31,31 @@ const (
 type DownstreamHandler struct {
 	limits Limits
 	next   queryrangebase.Handler
+
+	// Scheduler is the shared, process-wide worker pool downstream shard
+	// executions are dispatched through. When nil, the package-default
+	// Scheduler (sized by scheduler.DefaultWorkers) is used, so existing
+	// callers that construct DownstreamHandler as a struct literal keep
+	// working unchanged.
+	Scheduler *scheduler.Scheduler
+}
+
+var (
+	defaultSchedulerOnce sync.Once
+	defaultScheduler     *scheduler.Scheduler
+)
+
+// scheduler returns the worker pool this handler dispatches downstream shard
+// executions through, lazily starting the process-wide default the first
+// time it's needed.
+func (h DownstreamHandler) scheduler() *scheduler.Scheduler {
+	if h.Scheduler != nil {
+		return h.Scheduler
+	}
+	defaultSchedulerOnce.Do(func() {
+		defaultScheduler = scheduler.New(prometheus.DefaultRegisterer, scheduler.DefaultWorkers)
+	})
+	return defaultScheduler
 }
 
 func ParamsToLokiRequest(params logql.Params, shards logql.Shards) queryrangebase.Request {
@@ -52,41 +82,77 @@ func ParamsToLokiRequest(params logql.Params, shards logql.Shards) queryrangebas
 	}
 }
 
-// Note: After the introduction of the LimitedRoundTripper,
-// bounding concurrency in the downstreamer is mostly redundant
-// The reason we don't remove it is to prevent malicious queries
-// from creating an unreasonably large number of goroutines, such as
-// the case of a query like `a / a / a / a / a ..etc`, which could try
-// to shard each leg, quickly dispatching an unreasonable number of goroutines.
-// In the future, it's probably better to replace this with a channel based API
-// so we don't have to do all this ugly edge case handling/accounting
+// Downstream executions of a query are dispatched onto the shared Scheduler
+// rather than a per-query semaphore. The pool is bounded process wide (see
+// scheduler.DefaultWorkers), and each tenant's shards are additionally capped
+// at that tenant's MaxQueryParallelism (see tenantBudget) so a single tenant
+// can't occupy the whole pool. This also lets an adversarial query like
+// `a / a / a / a / a ..etc` be scheduled fairly alongside everything else, and
+// be rejected outright at enqueue time once its tenant has more shards queued
+// or running than a fixed multiple of that budget (see scheduler.Submit),
+// instead of relying on a
+// fresh, unbounded goroutine-per-shard fan-out.
 func (h DownstreamHandler) Downstreamer(ctx context.Context) logql.Downstreamer {
-	p := DefaultDownstreamConcurrency
-
-	// We may increase parallelism above the default,
-	// ensure we don't end up bottlenecking here.
-	if user, err := tenant.TenantID(ctx); err == nil {
-		if x := h.limits.MaxQueryParallelism(ctx, user); x > 0 {
-			p = x
+	// A query may be scoped to more than one tenant when the caller wants a
+	// single sharded logical query executed across tenants that share a
+	// schema. Fall back to the single ambient tenant otherwise.
+	tenantIDs, err := tenant.TenantIDs(ctx)
+	if err != nil || len(tenantIDs) == 0 {
+		if id, err := tenant.TenantID(ctx); err == nil {
+			tenantIDs = []string{id}
 		}
 	}
 
-	locks := make(chan struct{}, p)
-	for i := 0; i < p; i++ {
-		locks <- struct{}{}
-	}
 	return &instance{
-		parallelism: p,
-		locks:       locks,
-		handler:     h.next,
+		sched:     h.scheduler(),
+		limits:    h.limits,
+		handler:   h.next,
+		tenantIDs: tenantIDs,
 	}
 }
 
-// instance is an intermediate struct for controlling concurrency across a single query
+// instance is an intermediate struct for dispatching the shards of a single
+// query onto the shared scheduler.
 type instance struct {
-	parallelism int
-	locks       chan struct{}
-	handler     queryrangebase.Handler
+	sched   *scheduler.Scheduler
+	limits  Limits
+	handler queryrangebase.Handler
+
+	// tenantIDs holds the set of tenants this query should be fanned out
+	// across. Its zero value (nil/empty) preserves the historical
+	// single-ambient-tenant behavior.
+	tenantIDs []string
+}
+
+// primaryTenant returns the tenant a query's shards are queued under for
+// scheduling purposes. Cross-tenant fan-out (see downstreamAcrossTenants)
+// still executes against every tenant in tenantIDs; this only picks which
+// tenant's fair-share queue the work is billed against.
+func (in instance) primaryTenant() string {
+	if len(in.tenantIDs) == 0 {
+		return ""
+	}
+	return in.tenantIDs[0]
+}
+
+// partialResultsLimits is the subset of Limits that opts a tenant into
+// partial-results mode. It's checked via a type assertion (rather than
+// added directly to Limits' already-large method set) so existing Limits
+// implementations keep compiling and simply default to strict, all-or-
+// nothing query results until they add this method.
+type partialResultsLimits interface {
+	QueryPartialResults(ctx context.Context, tenantID string) bool
+}
+
+// partialResults reports whether a shard or tenant failure should degrade
+// the query to a partial result (annotated with a warning) instead of
+// failing it outright, per the primary tenant's Limits.
+func (in instance) partialResults(ctx context.Context) bool {
+	pr, ok := in.limits.(partialResultsLimits)
+	if !ok {
+		return false
+	}
+	return pr.QueryPartialResults(ctx, in.primaryTenant())
 }
 
 func (in instance) Downstream(ctx context.Context, queries []logql.DownstreamQuery) ([]logqlmodel.Result, error) {
@@ -96,77 +162,191 @@ func (in instance) Downstream(ctx context.Context, queries []logql.DownstreamQue
 		defer sp.Finish()
 		logger := spanlogger.FromContext(ctx)
 		defer logger.Finish()
-		level.Debug(logger).Log("shards", fmt.Sprintf("%+v", qry.Shards), "query", req.GetQuery(), "step", req.GetStep(), "handler", reflect.TypeOf(in.handler))
+		level.Debug(logger).Log("shards", fmt.Sprintf("%+v", qry.Shards), "query", req.GetQuery(), "step", req.GetStep(), "handler", reflect.TypeOf(in.handler), "tenants", fmt.Sprintf("%+v", in.tenantIDs))
 
-		res, err := in.handler.Do(ctx, req)
-		if err != nil {
-			return logqlmodel.Result{}, err
+		partial := in.partialResults(ctx)
+
+		if len(in.tenantIDs) <= 1 {
+			res, err := in.handler.Do(ctx, req)
+			if err != nil {
+				if partial {
+					return logqlmodel.Result{Warnings: []string{err.Error()}}, nil
+				}
+				return logqlmodel.Result{}, err
+			}
+			if partial {
+				return ResponseToResultPartial(res)
+			}
+			return ResponseToResult(res)
 		}
-		return ResponseToResult(res)
+
+		return in.downstreamAcrossTenants(ctx, req)
 	})
 }
 
+// downstreamAcrossTenants executes req once per tenant in in.tenantIDs, pinning
+// each attempt to its tenant by injecting that tenant's org ID into the
+// request context, and merges the per-tenant results into a single
+// logqlmodel.Result. This allows a single sharded logical query to run
+// against a set of tenants that share a schema, similar to how Thanos added a
+// configurable tenant header for multi-cluster reads.
+func (in instance) downstreamAcrossTenants(ctx context.Context, req queryrangebase.Request) (logqlmodel.Result, error) {
+	partial := in.partialResults(ctx)
+
+	results := make([]logqlmodel.Result, 0, len(in.tenantIDs))
+	for _, id := range in.tenantIDs {
+		tenantCtx := user.InjectOrgID(ctx, id)
+
+		res, err := in.handler.Do(tenantCtx, req)
+		if err != nil {
+			if partial {
+				results = append(results, logqlmodel.Result{Warnings: []string{fmt.Sprintf("tenant %s: %s", id, err)}})
+				continue
+			}
+			return logqlmodel.Result{}, fmt.Errorf("tenant %s: %w", id, err)
+		}
+
+		var result logqlmodel.Result
+		if partial {
+			result, err = ResponseToResultPartial(res)
+		} else {
+			result, err = ResponseToResult(res)
+		}
+		if err != nil {
+			if partial {
+				results = append(results, logqlmodel.Result{Warnings: []string{fmt.Sprintf("tenant %s: %s", id, err)}})
+				continue
+			}
+			return logqlmodel.Result{}, fmt.Errorf("tenant %s: %w", id, err)
+		}
+		results = append(results, result)
+	}
+
+	return mergeTenantResults(results)
+}
+
+// mergeTenantResults combines the per-tenant results of a single fanned-out
+// downstream query into one logqlmodel.Result, preserving each tenant's
+// contribution to the overall statistics and headers.
+func mergeTenantResults(results []logqlmodel.Result) (logqlmodel.Result, error) {
+	if len(results) == 0 {
+		return logqlmodel.Result{}, nil
+	}
+
+	merged := results[0]
+	merged.Warnings = logqlmodel.WarningsFromResults(results...)
+	for _, next := range results[1:] {
+		merged.Statistics.Merge(next.Statistics)
+		merged.Headers = append(merged.Headers, next.Headers...)
+
+		if next.Data == nil {
+			// A tenant that failed under partial-results mode contributes
+			// only a warning; there's nothing to merge into merged.Data.
+			continue
+		}
+
+		switch data := merged.Data.(type) {
+		case nil:
+			merged.Data = next.Data
+		case logqlmodel.Streams:
+			other, ok := next.Data.(logqlmodel.Streams)
+			if !ok {
+				return logqlmodel.Result{}, fmt.Errorf("cannot merge cross-tenant results of differing types (%T, %T)", merged.Data, next.Data)
+			}
+			merged.Data = append(data, other...)
+		default:
+			return logqlmodel.Result{}, fmt.Errorf("cross-tenant fan-out only supports merging log stream results, got %T", merged.Data)
+		}
+	}
+	return merged, nil
+}
+
 // For runs a function against a list of queries, collecting the results or returning an error. The indices are preserved such that input[i] maps to output[i].
 func (in instance) For(
 	ctx context.Context,
 	queries []logql.DownstreamQuery,
 	fn func(logql.DownstreamQuery) (logqlmodel.Result, error),
 ) ([]logqlmodel.Result, error) {
-	type resp struct {
-		i   int
-		res logqlmodel.Result
-		err error
-	}
-
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
-	ch := make(chan resp)
-
-	// Make one goroutine to dispatch the other goroutines, bounded by instance parallelism
-	go func() {
-		for i := 0; i < len(queries); i++ {
-			select {
-			case <-ctx.Done():
-				break
-			case <-in.locks:
-				go func(i int) {
-					// release lock back into pool
-					defer func() {
-						in.locks <- struct{}{}
-					}()
-
-					res, err := fn(queries[i])
-					response := resp{
-						i:   i,
-						res: res,
-						err: err,
-					}
-
-					// Feed the result into the channel unless the work has completed.
-					select {
-					case <-ctx.Done():
-					case ch <- response:
-					}
-				}(i)
-			}
-		}
-	}()
+
+	tenantID := in.primaryTenant()
+	partial := in.partialResults(ctx)
+
+	budget := tenantBudget(ctx, in.limits, tenantID)
+
+	resultChs := make([]<-chan scheduler.Result, len(queries))
+	for i, qry := range queries {
+		qry := qry
+		resultChs[i] = in.sched.Submit(ctx, scheduler.Job{
+			TenantID:       tenantID,
+			Priority:       jobPriority(ctx, in.limits, tenantID, qry),
+			MaxConcurrency: budget,
+			Fn: func(ctx context.Context) (interface{}, error) {
+				return fn(qry)
+			},
+		})
+	}
 
 	results := make([]logqlmodel.Result, len(queries))
-	for i := 0; i < len(queries); i++ {
+	for i, resultCh := range resultChs {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
-		case resp := <-ch:
-			if resp.err != nil {
-				return nil, resp.err
+		case res := <-resultCh:
+			if res.Err != nil {
+				if partial {
+					results[i] = logqlmodel.Result{Warnings: []string{res.Err.Error()}}
+					continue
+				}
+				return nil, res.Err
 			}
-			results[resp.i] = resp.res
+			results[i] = res.Value.(logqlmodel.Result)
 		}
 	}
 	return results, nil
 }
 
+// jobPriority derives a scheduling priority (lower runs first) from the
+// query's estimated cost divided by the tenant's configured parallelism
+// budget. Priority only orders jobs within a single tenant's queue, so a
+// query's cheaper shards run ahead of its costlier ones; across tenants the
+// scheduler dispatches round-robin regardless of priority.
+func jobPriority(ctx context.Context, limits Limits, tenantID string, qry logql.DownstreamQuery) int {
+	if limits == nil {
+		return 0
+	}
+	return queryCost(qry) / tenantBudget(ctx, limits, tenantID)
+}
+
+// tenantBudget returns the tenant's configured MaxQueryParallelism, the same
+// figure jobPriority uses for scheduling order and the scheduler.Job.MaxConcurrency
+// cap enforces as an actual ceiling on how many of the tenant's shards may run
+// at once across the shared pool. A nil Limits returns 0 (no individual cap,
+// same as historical pool-wide-only behavior).
+func tenantBudget(ctx context.Context, limits Limits, tenantID string) int {
+	if limits == nil {
+		return 0
+	}
+	budget := limits.MaxQueryParallelism(ctx, tenantID)
+	if budget <= 0 {
+		budget = DefaultDownstreamConcurrency
+	}
+	return budget
+}
+
+// queryCost approximates how much work a shard represents by its queried
+// time range; longer ranges are assumed to touch more chunks.
+func queryCost(qry logql.DownstreamQuery) int {
+	if qry.Params == nil {
+		return 1
+	}
+	if d := qry.Params.End().Sub(qry.Params.Start()); d > 0 {
+		return int(d.Seconds()) + 1
+	}
+	return 1
+}
+
 // convert to matrix
 func sampleStreamToMatrix(streams []queryrangebase.SampleStream) parser.Value {
 	xs := make(promql.Matrix, 0, len(streams))
@@ -177,13 +357,24 @@ func sampleStreamToMatrix(streams []queryrangebase.SampleStream) parser.Value {
 			x.Metric = append(x.Metric, labels.Label(l))
 		}
 
-		x.Points = make([]promql.Point, 0, len(stream.Samples))
+		x.Points = make([]promql.Point, 0, len(stream.Samples)+len(stream.Histograms))
 		for _, sample := range stream.Samples {
 			x.Points = append(x.Points, promql.Point{
 				T: sample.TimestampMs,
 				V: sample.Value,
 			})
 		}
+		for _, h := range stream.Histograms {
+			x.Points = append(x.Points, promql.Point{
+				T: h.TimestampMs,
+				H: h.Histogram,
+			})
+		}
+		// Float samples and native histogram samples arrive on separate
+		// slices of the wire format; a series never mixes the two, but
+		// resorting here keeps the resulting series correctly ordered
+		// regardless of which slice was populated.
+		sortPointsByTime(x.Points)
 
 		xs = append(xs, x)
 	}
@@ -199,9 +390,17 @@ func sampleStreamToVector(streams []queryrangebase.SampleStream) parser.Value {
 			x.Metric = append(x.Metric, labels.Label(l))
 		}
 
-		x.Point = promql.Point{
-			T: stream.Samples[0].TimestampMs,
-			V: stream.Samples[0].Value,
+		switch {
+		case len(stream.Samples) > 0:
+			x.Point = promql.Point{
+				T: stream.Samples[0].TimestampMs,
+				V: stream.Samples[0].Value,
+			}
+		case len(stream.Histograms) > 0:
+			x.Point = promql.Point{
+				T: stream.Histograms[0].TimestampMs,
+				H: stream.Histograms[0].Histogram,
+			}
 		}
 
 		xs = append(xs, x)
@@ -209,10 +408,36 @@ func sampleStreamToVector(streams []queryrangebase.SampleStream) parser.Value {
 	return xs
 }
 
+// sortPointsByTime orders points ascending by timestamp in place.
+func sortPointsByTime(points []promql.Point) {
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].T < points[j].T
+	})
+}
+
 func ResponseToResult(resp queryrangebase.Response) (logqlmodel.Result, error) {
+	return responseToResult(resp, false)
+}
+
+// ResponseToResultPartial behaves like ResponseToResult, except a non-empty
+// Error field on the response is treated as a soft failure: it's returned as
+// a warning on the Result rather than a hard error, so a caller executing
+// this response as one of several shards can degrade gracefully instead of
+// failing the whole query when a single ingester or store shard errors.
+func ResponseToResultPartial(resp queryrangebase.Response) (logqlmodel.Result, error) {
+	return responseToResult(resp, true)
+}
+
+func responseToResult(resp queryrangebase.Response, partial bool) (logqlmodel.Result, error) {
 	switch r := resp.(type) {
 	case *LokiResponse:
 		if r.Error != "" {
+			if partial {
+				return logqlmodel.Result{
+					Warnings: []string{fmt.Sprintf("%s: %s", r.ErrorType, r.Error)},
+					Headers:  resp.GetHeaders(),
+				}, nil
+			}
 			return logqlmodel.Result{}, fmt.Errorf("%s: %s", r.ErrorType, r.Error)
 		}
 
@@ -230,6 +455,12 @@ func ResponseToResult(resp queryrangebase.Response) (logqlmodel.Result, error) {
 
 	case *LokiPromResponse:
 		if r.Response.Error != "" {
+			if partial {
+				return logqlmodel.Result{
+					Warnings: []string{fmt.Sprintf("%s: %s", r.Response.ErrorType, r.Response.Error)},
+					Headers:  resp.GetHeaders(),
+				}, nil
+			}
 			return logqlmodel.Result{}, fmt.Errorf("%s: %s", r.Response.ErrorType, r.Response.Error)
 		}
 		if r.Response.Data.ResultType == loghttp.ResultTypeVector {
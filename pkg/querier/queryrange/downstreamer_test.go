@@ -0,0 +1,133 @@
+package queryrange
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/pkg/logql"
+	"github.com/grafana/loki/pkg/logqlmodel"
+	"github.com/grafana/loki/pkg/querier/queryrange/queryrangebase"
+	"github.com/grafana/loki/pkg/querier/queryrange/scheduler"
+)
+
+// TestInstance_For_PreservesOrderAcrossTenants asserts that when the same
+// logical shard is executed against N tenants and merged (as
+// instance.downstreamAcrossTenants does), For returns one merged result per
+// input query, and the per-tenant contributions within each merged result
+// keep the order they were dispatched in.
+func TestInstance_For_PreservesOrderAcrossTenants(t *testing.T) {
+	const nShards = 5
+	const nTenants = 3
+
+	queries := make([]logql.DownstreamQuery, nShards)
+	sched := scheduler.New(prometheus.NewRegistry(), nShards)
+	defer sched.Stop()
+	in := instance{sched: sched}
+
+	results, err := in.For(context.Background(), queries, func(_ logql.DownstreamQuery) (logqlmodel.Result, error) {
+		tenantResults := make([]logqlmodel.Result, nTenants)
+		for t := 0; t < nTenants; t++ {
+			tenantResults[t] = logqlmodel.Result{
+				Data: logqlmodel.Streams{
+					{Labels: fmt.Sprintf(`{tenant="%d"}`, t)},
+				},
+			}
+		}
+		return mergeTenantResults(tenantResults)
+	})
+	require.NoError(t, err)
+	require.Len(t, results, nShards)
+
+	for _, res := range results {
+		streams, ok := res.Data.(logqlmodel.Streams)
+		require.True(t, ok)
+		require.Len(t, streams, nTenants)
+		for i, s := range streams {
+			require.Equal(t, fmt.Sprintf(`{tenant="%d"}`, i), s.Labels)
+		}
+	}
+}
+
+func TestMergeTenantResults_TypeMismatch(t *testing.T) {
+	_, err := mergeTenantResults([]logqlmodel.Result{
+		{Data: logqlmodel.Streams{{Labels: `{tenant="0"}`}}},
+		{Data: promql.Vector{}},
+	})
+	require.Error(t, err)
+}
+
+// TestMergeTenantResults_SkipsFailedTenants covers the partial-results case:
+// a tenant that errored contributes only a warning (nil Data), which must not
+// be treated as a type mismatch against the tenants that did return data.
+func TestMergeTenantResults_SkipsFailedTenants(t *testing.T) {
+	merged, err := mergeTenantResults([]logqlmodel.Result{
+		{Warnings: []string{"tenant a: unavailable"}},
+		{Data: logqlmodel.Streams{{Labels: `{tenant="b"}`}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenant a: unavailable"}, merged.Warnings)
+	streams, ok := merged.Data.(logqlmodel.Streams)
+	require.True(t, ok)
+	require.Len(t, streams, 1)
+}
+
+func TestJobPriority_NoLimitsIsZero(t *testing.T) {
+	require.Equal(t, 0, jobPriority(context.Background(), nil, "tenant-a", logql.DownstreamQuery{}))
+}
+
+func TestQueryCost_NilParamsDefaultsToOne(t *testing.T) {
+	require.Equal(t, 1, queryCost(logql.DownstreamQuery{}))
+}
+
+func TestSampleStreamToMatrix_CarriesHistograms(t *testing.T) {
+	early := &histogram.FloatHistogram{Count: 10, Sum: 42}
+	late := &histogram.FloatHistogram{Count: 20, Sum: 84}
+	matrix := sampleStreamToMatrix([]queryrangebase.SampleStream{
+		{
+			Histograms: []queryrangebase.SampleHistogramPair{
+				{TimestampMs: 2, Histogram: late},
+				{TimestampMs: 1, Histogram: early},
+			},
+		},
+	})
+
+	series, ok := matrix.(promql.Matrix)
+	require.True(t, ok)
+	require.Len(t, series, 1)
+	require.Len(t, series[0].Points, 2)
+	require.Same(t, early, series[0].Points[0].H)
+	require.Same(t, late, series[0].Points[1].H)
+}
+
+func TestSampleStreamToVector_FallsBackToHistogramWhenNoFloatSample(t *testing.T) {
+	fh := &histogram.FloatHistogram{Count: 10, Sum: 42}
+	vector := sampleStreamToVector([]queryrangebase.SampleStream{
+		{
+			Histograms: []queryrangebase.SampleHistogramPair{
+				{TimestampMs: 5, Histogram: fh},
+			},
+		},
+	})
+
+	samples, ok := vector.(promql.Vector)
+	require.True(t, ok)
+	require.Len(t, samples, 1)
+	require.Same(t, fh, samples[0].Point.H)
+}
+
+func TestResponseToResultPartial_SoftensErrors(t *testing.T) {
+	resp := &LokiResponse{ErrorType: "Query", Error: "boom"}
+
+	result, err := ResponseToResultPartial(resp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Query: boom"}, result.Warnings)
+
+	_, err = ResponseToResult(resp)
+	require.Error(t, err)
+}
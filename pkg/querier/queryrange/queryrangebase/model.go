@@ -0,0 +1,116 @@
+// Package queryrangebase holds the request/response wire types the
+// queryrange package's downstreamer and codecs operate on. This checkout
+// only carries SampleStream and its sample/histogram payload, since that's
+// the subset sampleStreamToMatrix/sampleStreamToVector depend on; the
+// Request/Response/Handler interfaces and the Loki-specific request/response
+// types built on top of them live alongside the rest of this repo's HTTP
+// codecs, outside this checkout.
+package queryrangebase
+
+import (
+	"encoding/json"
+
+	"github.com/prometheus/prometheus/model/histogram"
+)
+
+// LabelAdapter mirrors labels.Label's field layout so a []LabelAdapter can be
+// converted element-wise to labels.Label without a copy, the same way
+// logproto.LabelAdapter does for the rest of this repo's wire types.
+type LabelAdapter struct {
+	Name  string
+	Value string
+}
+
+// SampleStream holds a single series' samples over a queried range, as
+// returned by a range or instant metric query.
+type SampleStream struct {
+	Labels []LabelAdapter
+	// Samples holds this series' float samples.
+	Samples []SampleEntry
+	// Histograms holds this series' native histogram samples. A series
+	// never mixes float and native-histogram samples, but the two are kept
+	// on separate slices because they arrive (and marshal to JSON) in
+	// different shapes.
+	Histograms []SampleHistogramPair
+}
+
+// SampleEntry is a single float sample of a SampleStream.
+type SampleEntry struct {
+	TimestampMs int64
+	Value       float64
+}
+
+// SampleHistogramPair is a single native histogram sample of a SampleStream.
+type SampleHistogramPair struct {
+	TimestampMs int64
+	Histogram   *histogram.FloatHistogram
+}
+
+// sampleHistogramPairWire is the JSON shape of a SampleHistogramPair's
+// histogram half, mirroring the fields of histogram.FloatHistogram that are
+// needed to reconstruct it losslessly.
+type sampleHistogramPairWire struct {
+	Schema          int32            `json:"schema"`
+	ZeroThreshold   float64          `json:"zero_threshold"`
+	ZeroCount       float64          `json:"zero_count"`
+	Count           float64          `json:"count"`
+	Sum             float64          `json:"sum"`
+	PositiveSpans   []histogram.Span `json:"positive_spans,omitempty"`
+	PositiveBuckets []float64        `json:"positive_buckets,omitempty"`
+	NegativeSpans   []histogram.Span `json:"negative_spans,omitempty"`
+	NegativeBuckets []float64        `json:"negative_buckets,omitempty"`
+}
+
+// MarshalJSON encodes the pair as a Prometheus-style [timestamp, histogram]
+// tuple, with the timestamp in fractional seconds, so a LokiPromResponse
+// carrying native histogram samples round-trips through JSON.
+func (p SampleHistogramPair) MarshalJSON() ([]byte, error) {
+	h := p.Histogram
+	if h == nil {
+		h = &histogram.FloatHistogram{}
+	}
+	wire := sampleHistogramPairWire{
+		Schema:          h.Schema,
+		ZeroThreshold:   h.ZeroThreshold,
+		ZeroCount:       h.ZeroCount,
+		Count:           h.Count,
+		Sum:             h.Sum,
+		PositiveSpans:   h.PositiveSpans,
+		PositiveBuckets: h.PositiveBuckets,
+		NegativeSpans:   h.NegativeSpans,
+		NegativeBuckets: h.NegativeBuckets,
+	}
+	return json.Marshal([2]interface{}{float64(p.TimestampMs) / 1000, wire})
+}
+
+// UnmarshalJSON decodes a pair encoded by MarshalJSON.
+func (p *SampleHistogramPair) UnmarshalJSON(b []byte) error {
+	var tuple [2]json.RawMessage
+	if err := json.Unmarshal(b, &tuple); err != nil {
+		return err
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(tuple[0], &seconds); err != nil {
+		return err
+	}
+
+	var wire sampleHistogramPairWire
+	if err := json.Unmarshal(tuple[1], &wire); err != nil {
+		return err
+	}
+
+	p.TimestampMs = int64(seconds * 1000)
+	p.Histogram = &histogram.FloatHistogram{
+		Schema:          wire.Schema,
+		ZeroThreshold:   wire.ZeroThreshold,
+		ZeroCount:       wire.ZeroCount,
+		Count:           wire.Count,
+		Sum:             wire.Sum,
+		PositiveSpans:   wire.PositiveSpans,
+		PositiveBuckets: wire.PositiveBuckets,
+		NegativeSpans:   wire.NegativeSpans,
+		NegativeBuckets: wire.NegativeBuckets,
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package queryrangebase
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleHistogramPair_JSONRoundTrip(t *testing.T) {
+	pair := SampleHistogramPair{
+		TimestampMs: 1500,
+		Histogram: &histogram.FloatHistogram{
+			Schema:          1,
+			ZeroThreshold:   0.001,
+			ZeroCount:       2,
+			Count:           10,
+			Sum:             42.5,
+			PositiveSpans:   []histogram.Span{{Offset: 0, Length: 2}},
+			PositiveBuckets: []float64{1, 2},
+		},
+	}
+
+	b, err := json.Marshal(pair)
+	require.NoError(t, err)
+
+	var got SampleHistogramPair
+	require.NoError(t, json.Unmarshal(b, &got))
+
+	require.Equal(t, pair.TimestampMs, got.TimestampMs)
+	require.Equal(t, pair.Histogram.Schema, got.Histogram.Schema)
+	require.Equal(t, pair.Histogram.ZeroThreshold, got.Histogram.ZeroThreshold)
+	require.Equal(t, pair.Histogram.ZeroCount, got.Histogram.ZeroCount)
+	require.Equal(t, pair.Histogram.Count, got.Histogram.Count)
+	require.Equal(t, pair.Histogram.Sum, got.Histogram.Sum)
+	require.Equal(t, pair.Histogram.PositiveSpans, got.Histogram.PositiveSpans)
+	require.Equal(t, pair.Histogram.PositiveBuckets, got.Histogram.PositiveBuckets)
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "loki"
+
+type metrics struct {
+	queueDepth   *prometheus.GaugeVec
+	waitDuration *prometheus.HistogramVec
+	preemptions  *prometheus.CounterVec
+	rejections   *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) *metrics {
+	return &metrics{
+		queueDepth: promauto.With(reg).NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "query_scheduler_queue_length",
+			Help:      "Number of downstream shard jobs queued per tenant, waiting for a worker.",
+		}, []string{"tenant"}),
+		waitDuration: promauto.With(reg).NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "query_scheduler_queue_duration_seconds",
+			Help:      "Time downstream shard jobs spent queued before a worker picked them up.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"tenant"}),
+		preemptions: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_scheduler_preemptions_total",
+			Help:      "Number of queued downstream shard jobs abandoned before a worker started them, because their query context was canceled or the scheduler was stopped.",
+		}, []string{"tenant"}),
+		rejections: promauto.With(reg).NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "query_scheduler_rejections_total",
+			Help:      "Number of downstream shard jobs rejected at submission time because the tenant already had its allotted concurrent/queued work outstanding.",
+		}, []string{"tenant"}),
+	}
+}
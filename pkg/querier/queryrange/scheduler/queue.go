@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// entry wraps a submitted Job with its queueing bookkeeping.
+type entry struct {
+	job        Job
+	ctx        context.Context
+	resultCh   chan<- Result
+	enqueuedAt time.Time
+	seq        uint64
+}
+
+// tenantQueue is a container/heap ordering a single tenant's pending jobs by
+// Priority (lower first), breaking ties in FIFO order so equal-priority jobs
+// from the same tenant still run in submission order.
+type tenantQueue struct {
+	entries []*entry
+	nextSeq uint64
+
+	// limit is the tenant's MaxConcurrency, as last reported by a submitted
+	// Job with a positive MaxConcurrency; a Job with MaxConcurrency <= 0
+	// leaves it unchanged. <= 0 (no such Job yet) means the tenant has no
+	// individual cap beyond the pool's total worker count.
+	limit int
+}
+
+func (q *tenantQueue) Len() int { return len(q.entries) }
+
+func (q *tenantQueue) Less(i, j int) bool {
+	if q.entries[i].job.Priority != q.entries[j].job.Priority {
+		return q.entries[i].job.Priority < q.entries[j].job.Priority
+	}
+	return q.entries[i].seq < q.entries[j].seq
+}
+
+func (q *tenantQueue) Swap(i, j int) {
+	q.entries[i], q.entries[j] = q.entries[j], q.entries[i]
+}
+
+func (q *tenantQueue) Push(x interface{}) {
+	e := x.(*entry)
+	e.enqueuedAt = now()
+	e.seq = q.nextSeq
+	q.nextSeq++
+	q.entries = append(q.entries, e)
+}
+
+func (q *tenantQueue) Pop() interface{} {
+	old := q.entries
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	q.entries = old[:n-1]
+	return e
+}
+
+// now and sinceSeconds are indirected so tests can stub timing without
+// depending on wall-clock flakiness.
+var now = time.Now
+
+func sinceSeconds(t time.Time) float64 {
+	return now().Sub(t).Seconds()
+}
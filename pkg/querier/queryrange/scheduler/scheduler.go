@@ -0,0 +1,250 @@
+// Package scheduler implements a small, process-wide, tenant-fair work
+// scheduler for downstream shard executions issued by the query-range
+// downstreamer. It replaces the per-query `locks chan struct{}` semaphore
+// that used to live on DownstreamHandler's instance: rather than a
+// fixed-size pool re-created (and goroutine-spawned against) for every
+// query, all queries in the process share one bounded worker pool, ordered
+// by priority within their tenant and dispatched round-robin across tenants
+// so a single noisy tenant can't starve the rest.
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultWorkers bounds the number of jobs executing concurrently across the
+// whole process, mirroring the previous DefaultDownstreamConcurrency default.
+const DefaultWorkers = 128
+
+// maxQueuedPerTenant bounds, as a multiple of a tenant's MaxConcurrency, how
+// much of that tenant's work may sit queued (queued + running) before Submit
+// starts rejecting it outright. It exists so an adversarial query fanning out
+// into far more shards than its tenant's parallelism budget allows is turned
+// away at enqueue time instead of piling up unboundedly behind fairly-shared
+// workers.
+const maxQueuedPerTenant = 4
+
+// Job is a unit of downstream work submitted to a Scheduler.
+type Job struct {
+	// TenantID is used to fairly share the worker pool across tenants.
+	TenantID string
+	// Priority orders jobs within a tenant's queue; lower values run first.
+	Priority int
+	// MaxConcurrency caps how many of this tenant's jobs may run at once
+	// across the shared pool, mirroring the tenant's configured
+	// MaxQueryParallelism. <= 0 means the tenant is only bounded by the
+	// pool's total worker count, not individually.
+	MaxConcurrency int
+	// Fn is the work to execute. It must respect ctx cancellation.
+	Fn func(ctx context.Context) (interface{}, error)
+}
+
+// Result is delivered on the channel returned by Submit once Job.Fn
+// completes, or as soon as the job's context is canceled if it never got a
+// chance to run.
+type Result struct {
+	Value interface{}
+	Err   error
+}
+
+// Scheduler is a bounded worker pool shared across all downstream query
+// executions in a process. Jobs are queued per tenant and dispatched
+// round-robin across tenants with pending work, so tenants get a fair share
+// of the pool regardless of how many shards any single query fans out into.
+type Scheduler struct {
+	metrics *metrics
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[string]*tenantQueue
+	active  map[string]int // count of each tenant's jobs currently running
+	order   []string       // round-robin cursor of tenant IDs with pending work
+	pos     int
+	stopped bool
+
+	wg sync.WaitGroup
+}
+
+// New starts a Scheduler with the given worker count and begins its worker
+// pool. Callers should treat the returned Scheduler as a singleton shared by
+// every DownstreamHandler in the process.
+func New(reg prometheus.Registerer, workers int) *Scheduler {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	s := &Scheduler{
+		metrics: newMetrics(reg),
+		queues:  make(map[string]*tenantQueue),
+		active:  make(map[string]int),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	return s
+}
+
+// Stop fails every queued job with context.Canceled and waits for in-flight
+// jobs to finish on their own before returning.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	for tenant, q := range s.queues {
+		for q.Len() > 0 {
+			e := heap.Pop(q).(*entry)
+			s.metrics.queueDepth.WithLabelValues(tenant).Dec()
+			s.metrics.preemptions.WithLabelValues(tenant).Inc()
+			e.resultCh <- Result{Err: context.Canceled}
+		}
+	}
+	s.queues = make(map[string]*tenantQueue)
+	s.order = nil
+	s.active = make(map[string]int)
+	s.mu.Unlock()
+
+	s.cond.Broadcast()
+	s.wg.Wait()
+}
+
+// Submit enqueues job under its tenant's queue and returns a channel that
+// will receive exactly one Result once the job runs, is preempted because
+// ctx was canceled before it got a turn, or the Scheduler is stopped. A job
+// submitted for a tenant that already has maxQueuedPerTenant times its
+// MaxConcurrency worth of work queued or running is rejected immediately
+// instead of being queued, so a single adversarial query can't pile
+// unbounded work behind the tenant's fair share of the pool.
+func (s *Scheduler) Submit(ctx context.Context, job Job) <-chan Result {
+	resultCh := make(chan Result, 1)
+
+	s.mu.Lock()
+	q, ok := s.queues[job.TenantID]
+	if !ok {
+		q = &tenantQueue{}
+		s.queues[job.TenantID] = q
+		s.order = append(s.order, job.TenantID)
+	}
+	if job.MaxConcurrency > 0 {
+		q.limit = job.MaxConcurrency
+	}
+	if q.limit > 0 && q.Len()+s.active[job.TenantID] >= q.limit*maxQueuedPerTenant {
+		s.mu.Unlock()
+		s.metrics.rejections.WithLabelValues(job.TenantID).Inc()
+		resultCh <- Result{Err: fmt.Errorf("tenant %s exceeded its outstanding work limit (%d queued or running); query rejected", job.TenantID, q.limit*maxQueuedPerTenant)}
+		return resultCh
+	}
+	heap.Push(q, &entry{job: job, ctx: ctx, resultCh: resultCh})
+	s.metrics.queueDepth.WithLabelValues(job.TenantID).Inc()
+	s.mu.Unlock()
+
+	s.cond.Signal()
+	return resultCh
+}
+
+// worker repeatedly pulls the next job to run, round-robining fairly across
+// tenants, until the Scheduler is stopped.
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for {
+		e, ok := s.dequeue()
+		if !ok {
+			return
+		}
+		s.run(e)
+	}
+}
+
+// dequeue blocks until a job is available or the Scheduler is stopped.
+func (s *Scheduler) dequeue() (*entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if e := s.popNext(); e != nil {
+			return e, true
+		}
+		if s.stopped {
+			return nil, false
+		}
+		s.cond.Wait()
+	}
+}
+
+// popNext pops the highest-priority job from the next tenant in round-robin
+// order whose concurrency cap isn't already exhausted, skipping (and
+// forgetting) tenants whose queues have drained. Tenants at their cap are
+// skipped but not forgotten, since they still have work waiting for a
+// running job to finish and free up a slot. Caller must hold s.mu.
+func (s *Scheduler) popNext() *entry {
+	// Snapshot the tenant count before scanning: popNext shrinks s.order as it
+	// forgets drained tenants, so bounding attempts against the live length
+	// would give up early and skip tenants further down the round-robin order
+	// that still have pending work.
+	n := len(s.order)
+	for attempts := 0; attempts < n; attempts++ {
+		if len(s.order) == 0 {
+			return nil
+		}
+		s.pos %= len(s.order)
+		tenant := s.order[s.pos]
+		q := s.queues[tenant]
+
+		if q.Len() == 0 {
+			s.order = append(s.order[:s.pos], s.order[s.pos+1:]...)
+			delete(s.queues, tenant)
+			// Keep the running count of a tenant whose jobs are still
+			// executing, so its MaxConcurrency cap holds if it submits more.
+			if s.active[tenant] == 0 {
+				delete(s.active, tenant)
+			}
+			continue
+		}
+
+		if q.limit > 0 && s.active[tenant] >= q.limit {
+			s.pos++
+			continue
+		}
+
+		e := heap.Pop(q).(*entry)
+		s.metrics.queueDepth.WithLabelValues(tenant).Dec()
+		s.active[tenant]++
+		s.pos++
+		return e
+	}
+	return nil
+}
+
+func (s *Scheduler) run(e *entry) {
+	defer s.release(e.job.TenantID)
+	s.metrics.waitDuration.WithLabelValues(e.job.TenantID).Observe(sinceSeconds(e.enqueuedAt))
+
+	select {
+	case <-e.ctx.Done():
+		s.metrics.preemptions.WithLabelValues(e.job.TenantID).Inc()
+		e.resultCh <- Result{Err: e.ctx.Err()}
+		return
+	default:
+	}
+
+	v, err := e.job.Fn(e.ctx)
+	e.resultCh <- Result{Value: v, Err: err}
+}
+
+// release returns a tenant's concurrency slot to the pool once one of its
+// jobs finishes running, and wakes any worker that gave up popNext because
+// the tenant was at its cap.
+func (s *Scheduler) release(tenant string) {
+	s.mu.Lock()
+	if s.active[tenant] > 0 {
+		s.active[tenant]--
+	}
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
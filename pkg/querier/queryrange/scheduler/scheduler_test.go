@@ -0,0 +1,260 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_RunsAllJobs(t *testing.T) {
+	s := New(prometheus.NewRegistry(), 2)
+	defer s.Stop()
+
+	const n = 20
+	chs := make([]<-chan Result, n)
+	for i := 0; i < n; i++ {
+		i := i
+		chs[i] = s.Submit(context.Background(), Job{
+			TenantID: "a",
+			Fn: func(ctx context.Context) (interface{}, error) {
+				return i, nil
+			},
+		})
+	}
+
+	for i, ch := range chs {
+		res := <-ch
+		require.NoError(t, res.Err)
+		require.Equal(t, i, res.Value)
+	}
+}
+
+func TestScheduler_FairnessAcrossTenants(t *testing.T) {
+	s := New(prometheus.NewRegistry(), 1) // single worker forces strict interleaving
+	defer s.Stop()
+
+	var mu sync.Mutex
+	var order []string
+
+	// Occupy the single worker until every job below is queued, so the
+	// dispatch order is decided by the round-robin alone.
+	block := make(chan struct{})
+	started := make(chan struct{})
+	s.Submit(context.Background(), Job{
+		TenantID: "a",
+		Fn: func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-block
+			return nil, nil
+		},
+	})
+	<-started
+
+	var wg sync.WaitGroup
+	const jobsPerTenant = 5
+	for _, tenant := range []string{"a", "b"} {
+		tenant := tenant
+		for i := 0; i < jobsPerTenant; i++ {
+			wg.Add(1)
+			s.Submit(context.Background(), Job{
+				TenantID: tenant,
+				Fn: func(ctx context.Context) (interface{}, error) {
+					defer wg.Done()
+					mu.Lock()
+					order = append(order, tenant)
+					mu.Unlock()
+					return nil, nil
+				},
+			})
+		}
+	}
+	close(block)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, order, 2*jobsPerTenant)
+	for i := 1; i < len(order); i++ {
+		require.NotEqual(t, order[i-1], order[i], "tenant %s ran twice in a row at position %d", order[i], i)
+	}
+
+	counts := map[string]int{}
+	for _, tenant := range order {
+		counts[tenant]++
+	}
+	require.Equal(t, jobsPerTenant, counts["a"])
+	require.Equal(t, jobsPerTenant, counts["b"])
+}
+
+func TestScheduler_PriorityWithinTenant(t *testing.T) {
+	s := New(prometheus.NewRegistry(), 1)
+
+	block := make(chan struct{})
+	first := s.Submit(context.Background(), Job{
+		TenantID: "a",
+		Fn: func(ctx context.Context) (interface{}, error) {
+			<-block // occupy the single worker until both jobs below are queued
+			return "blocker", nil
+		},
+	})
+
+	var mu sync.Mutex
+	var order []int
+	done := make(chan struct{}, 2)
+	submit := func(priority int) {
+		s.Submit(context.Background(), Job{
+			TenantID: "a",
+			Priority: priority,
+			Fn: func(ctx context.Context) (interface{}, error) {
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+				done <- struct{}{}
+				return nil, nil
+			},
+		})
+	}
+	submit(10) // low priority, submitted first
+	submit(0)  // high priority, submitted second, should still run first
+
+	close(block)
+	require.NoError(t, (<-first).Err)
+	<-done
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []int{0, 10}, order)
+
+	s.Stop()
+}
+
+// TestScheduler_PopNext_SkipsDrainedTenantsWithoutGivingUpEarly covers a
+// regression where popNext bounded its scan against len(s.order) re-read
+// after drained tenants were spliced out mid-loop, so it could return nil
+// even though a tenant later in the round-robin order still had work queued.
+func TestScheduler_PopNext_SkipsDrainedTenantsWithoutGivingUpEarly(t *testing.T) {
+	s := &Scheduler{
+		metrics: newMetrics(prometheus.NewRegistry()),
+		queues: map[string]*tenantQueue{
+			"t1": {},
+			"t2": {},
+			"t3": {},
+		},
+		active: map[string]int{},
+		order:  []string{"t1", "t2", "t3"},
+	}
+	heap.Push(s.queues["t3"], &entry{job: Job{TenantID: "t3"}, resultCh: make(chan Result, 1)})
+
+	e := s.popNext()
+	require.NotNil(t, e)
+	require.Equal(t, "t3", e.job.TenantID)
+}
+
+func TestScheduler_EnforcesPerTenantMaxConcurrency(t *testing.T) {
+	s := New(prometheus.NewRegistry(), 4) // pool is wider than tenant "a"'s cap
+	defer s.Stop()
+
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	release := make(chan struct{})
+
+	const n = 8
+	chs := make([]<-chan Result, n)
+	for i := 0; i < n; i++ {
+		chs[i] = s.Submit(context.Background(), Job{
+			TenantID:       "a",
+			MaxConcurrency: 2,
+			Fn: func(ctx context.Context) (interface{}, error) {
+				mu.Lock()
+				inFlight++
+				if inFlight > peak {
+					peak = inFlight
+				}
+				mu.Unlock()
+
+				<-release
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				return nil, nil
+			},
+		})
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the pool settle against the cap
+	mu.Lock()
+	require.LessOrEqual(t, peak, 2)
+	mu.Unlock()
+
+	close(release)
+	for _, ch := range chs {
+		require.NoError(t, (<-ch).Err)
+	}
+}
+
+func TestScheduler_Submit_RejectsOverQueuedTenant(t *testing.T) {
+	s := New(prometheus.NewRegistry(), 1)
+	defer s.Stop()
+
+	block := make(chan struct{})
+	started := make(chan struct{})
+	blocker := s.Submit(context.Background(), Job{
+		TenantID:       "a",
+		MaxConcurrency: 1,
+		Fn: func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-block
+			return nil, nil
+		},
+	})
+	<-started // blocker now holds tenant "a"'s single concurrency slot
+
+	noop := func(ctx context.Context) (interface{}, error) { return nil, nil }
+	for i := 0; i < maxQueuedPerTenant-1; i++ {
+		s.Submit(context.Background(), Job{TenantID: "a", MaxConcurrency: 1, Fn: noop})
+	}
+
+	rejected := s.Submit(context.Background(), Job{TenantID: "a", MaxConcurrency: 1, Fn: noop})
+	res := <-rejected
+	require.Error(t, res.Err)
+
+	close(block)
+	require.NoError(t, (<-blocker).Err)
+}
+
+func TestScheduler_StopPreemptsQueuedJobs(t *testing.T) {
+	s := New(prometheus.NewRegistry(), 1)
+
+	block := make(chan struct{})
+	blocker := s.Submit(context.Background(), Job{
+		TenantID: "a",
+		Fn: func(ctx context.Context) (interface{}, error) {
+			<-block
+			return nil, nil
+		},
+	})
+
+	queued := s.Submit(context.Background(), Job{
+		TenantID: "a",
+		Fn: func(ctx context.Context) (interface{}, error) {
+			return nil, nil
+		},
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(block)
+	}()
+	s.Stop()
+
+	res := <-queued
+	require.ErrorIs(t, res.Err, context.Canceled)
+	<-blocker
+}